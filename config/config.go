@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/caarlos0/env/v10"
+	"github.com/ferretcode/locomotive/filter"
 )
 
 type AdditionalHeaders map[string]string
@@ -15,7 +16,7 @@ func (h *AdditionalHeaders) UnmarshalText(envByte []byte) error {
 	envString := string(envByte)
 	headers := make(map[string]string)
 
-	headerPairs := strings.SplitN(envString, ";", 2)
+	headerPairs := strings.Split(envString, ";")
 
 	for _, header := range headerPairs {
 		keyValue := strings.SplitN(header, "=", 2)
@@ -67,6 +68,48 @@ type Config struct {
 	LogsContentFilterSlack   string `env:"LOGS_CONTENT_FILTER_SLACK"`
 	LogsContentFilterLoki    string `env:"LOGS_CONTENT_FILTER_LOKI"`
 	LogsContentFilterWebhook string `env:"LOGS_CONTENT_FILTER_WEBHOOK"`
+
+	// LogsFilterMode* picks how a LOGS_CONTENT_FILTER* value without a
+	// re:/attr:/tag:/cel: prefix is interpreted for that sink.
+	LogsFilterModeGlobal  filter.Mode `env:"LOGS_FILTER_MODE" envDefault:"substring"`
+	LogsFilterModeDiscord filter.Mode `env:"LOGS_FILTER_MODE_DISCORD" envDefault:"substring"`
+	LogsFilterModeSlack   filter.Mode `env:"LOGS_FILTER_MODE_SLACK" envDefault:"substring"`
+	LogsFilterModeLoki    filter.Mode `env:"LOGS_FILTER_MODE_LOKI" envDefault:"substring"`
+	LogsFilterModeWebhook filter.Mode `env:"LOGS_FILTER_MODE_WEBHOOK" envDefault:"substring"`
+	LogsFilterModeOtlp    filter.Mode `env:"LOGS_FILTER_MODE_OTLP" envDefault:"substring"`
+
+	// Content filters compiled once at load time, so a malformed filter
+	// fails fast in GetConfig rather than silently matching or dropping
+	// every log at runtime.
+	LogsContentFilterProgramGlobal  filter.FilterProgram
+	LogsContentFilterProgramDiscord filter.FilterProgram
+	LogsContentFilterProgramSlack   filter.FilterProgram
+	LogsContentFilterProgramLoki    filter.FilterProgram
+	LogsContentFilterProgramWebhook filter.FilterProgram
+	LogsContentFilterProgramOtlp    filter.FilterProgram
+
+	OtlpEndpoint           string            `env:"OTLP_ENDPOINT"`
+	OtlpHeaders            AdditionalHeaders `env:"OTLP_HEADERS"`
+	OtlpProtocol           string            `env:"OTLP_PROTOCOL" envDefault:"http"`
+	OtlpResourceAttributes AdditionalHeaders `env:"OTLP_RESOURCE_ATTRIBUTES"`
+
+	LogsFilterOtlp        []string `env:"LOGS_FILTER_OTLP" envSeparator:","`
+	LogsContentFilterOtlp string   `env:"LOGS_CONTENT_FILTER_OTLP"`
+
+	WalDir       string        `env:"WAL_DIR"`
+	WalMaxBytes  int64         `env:"WAL_MAX_BYTES" envDefault:"104857600"`
+	WalRetention time.Duration `env:"WAL_RETENTION" envDefault:"168h"`
+
+	// ReplayFrom re-sends already-WAL'd logs to every sink, either from a
+	// RFC3339 timestamp or a raw WAL offset. It requires WAL_DIR to be set.
+	ReplayFrom string `env:"REPLAY_FROM"`
+
+	// AutoDiscoverServices keeps re-polling GetAllServicesInEnvironment for
+	// the lifetime of the subscription and resubscribes on change, instead
+	// of only discovering services once at startup. It requires
+	// RAILWAY_PROJECT_ID and leaves static TRAIN deployments unaffected.
+	AutoDiscoverServices     bool          `env:"AUTO_DISCOVER_SERVICES" envDefault:"false"`
+	ServiceDiscoveryInterval time.Duration `env:"SERVICE_DISCOVERY_INTERVAL" envDefault:"60s"`
 }
 
 func GetConfig() (*Config, error) {
@@ -104,8 +147,53 @@ func GetConfig() (*Config, error) {
 		return nil, errors.New("invalid Slack webhook URL")
 	}
 
-	if config.DiscordWebhookUrl == "" && config.IngestUrl == "" && config.SlackWebhookUrl == "" && config.LokiIngestUrl == "" {
-		return nil, errors.New("specify either a discord webhook url or an ingest url or a slack webhook url or a loki url")
+	if config.DiscordWebhookUrl == "" && config.IngestUrl == "" && config.SlackWebhookUrl == "" && config.LokiIngestUrl == "" && config.OtlpEndpoint == "" {
+		return nil, errors.New("specify either a discord webhook url or an ingest url or a slack webhook url or a loki url or an otlp endpoint")
+	}
+
+	if config.OtlpEndpoint != "" && config.OtlpProtocol != "http" && config.OtlpProtocol != "grpc" {
+		return nil, errors.New("OTLP_PROTOCOL must be either \"http\" or \"grpc\"")
+	}
+
+	if config.WalMaxBytes <= 0 {
+		return nil, errors.New("WAL_MAX_BYTES must be greater than 0")
+	}
+
+	if config.ReplayFrom != "" && config.WalDir == "" {
+		return nil, errors.New("REPLAY_FROM requires WAL_DIR to be set")
+	}
+
+	if config.AutoDiscoverServices && config.ProjectId == "" {
+		return nil, errors.New("AUTO_DISCOVER_SERVICES requires RAILWAY_PROJECT_ID to be set")
+	}
+
+	if config.AutoDiscoverServices && len(config.Train) > 0 {
+		return nil, errors.New("AUTO_DISCOVER_SERVICES cannot be used together with TRAIN")
+	}
+
+	if config.AutoDiscoverServices && config.ServiceDiscoveryInterval <= 0 {
+		return nil, errors.New("SERVICE_DISCOVERY_INTERVAL must be greater than 0")
+	}
+
+	for _, compilable := range []struct {
+		name    string
+		raw     string
+		mode    filter.Mode
+		program *filter.FilterProgram
+	}{
+		{"LOGS_CONTENT_FILTER", config.LogsContentFilterGlobal, config.LogsFilterModeGlobal, &config.LogsContentFilterProgramGlobal},
+		{"LOGS_CONTENT_FILTER_DISCORD", config.LogsContentFilterDiscord, config.LogsFilterModeDiscord, &config.LogsContentFilterProgramDiscord},
+		{"LOGS_CONTENT_FILTER_SLACK", config.LogsContentFilterSlack, config.LogsFilterModeSlack, &config.LogsContentFilterProgramSlack},
+		{"LOGS_CONTENT_FILTER_LOKI", config.LogsContentFilterLoki, config.LogsFilterModeLoki, &config.LogsContentFilterProgramLoki},
+		{"LOGS_CONTENT_FILTER_WEBHOOK", config.LogsContentFilterWebhook, config.LogsFilterModeWebhook, &config.LogsContentFilterProgramWebhook},
+		{"LOGS_CONTENT_FILTER_OTLP", config.LogsContentFilterOtlp, config.LogsFilterModeOtlp, &config.LogsContentFilterProgramOtlp},
+	} {
+		program, err := filter.CompileCached(compilable.raw, compilable.mode)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling %s: %w", compilable.name, err)
+		}
+
+		*compilable.program = program
 	}
 
 	return &config, nil
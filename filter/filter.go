@@ -0,0 +1,301 @@
+// Package filter compiles the LOGS_CONTENT_FILTER* expressions into
+// FilterPrograms that can be evaluated against a structured view of a log,
+// rather than a flat string. Expressions can be prefixed to pick a mode
+// regardless of the sink's default LOGS_FILTER_MODE:
+//
+//	re:^ERROR.*timeout$           RE2 regex against the log message
+//	attr:http.status_code>=500    comparison against a log attribute
+//	tag:service_name=api          comparison against a log tag
+//	cel:severity == "ERROR"       CEL expression over severity/message/attributes/tags
+//
+// An expression with no prefix is interpreted according to Mode (substring
+// by default).
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// Mode selects how an unprefixed filter expression is interpreted.
+type Mode string
+
+const (
+	ModeSubstring Mode = "substring"
+	ModeRegex     Mode = "regex"
+	ModeCEL       Mode = "cel"
+)
+
+// LogView is the typed, read-only view of a log that field-scoped and CEL
+// filters are evaluated against.
+type LogView struct {
+	Severity   string
+	Message    string
+	Attributes map[string]string
+	Tags       map[string]string
+}
+
+// FilterProgram is a compiled filter expression, ready to be evaluated
+// against logs without re-parsing it every time.
+type FilterProgram interface {
+	Match(log LogView) bool
+}
+
+// Compile parses and compiles a single filter expression into a
+// FilterProgram. An empty expression always matches.
+func Compile(raw string, mode Mode) (FilterProgram, error) {
+	if raw == "" {
+		return alwaysMatch{}, nil
+	}
+
+	switch {
+	case strings.HasPrefix(raw, "re:"):
+		return compileRegex(strings.TrimPrefix(raw, "re:"))
+	case strings.HasPrefix(raw, "attr:"):
+		return compileField(sourceAttribute, strings.TrimPrefix(raw, "attr:"))
+	case strings.HasPrefix(raw, "tag:"):
+		return compileField(sourceTag, strings.TrimPrefix(raw, "tag:"))
+	case strings.HasPrefix(raw, "cel:"):
+		return compileCEL(strings.TrimPrefix(raw, "cel:"))
+	}
+
+	switch mode {
+	case "", ModeSubstring:
+		return substringProgram{substr: raw}, nil
+	case ModeRegex:
+		return compileRegex(raw)
+	case ModeCEL:
+		return compileCEL(raw)
+	default:
+		return nil, fmt.Errorf("unknown filter mode %q", mode)
+	}
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]FilterProgram{}
+)
+
+// CompileCached behaves like Compile, but reuses a previously compiled
+// program for the same (raw, mode) pair instead of recompiling it. Config
+// loading and the inline filtering in SubscribeToLogs share this cache.
+func CompileCached(raw string, mode Mode) (FilterProgram, error) {
+	key := string(mode) + "\x00" + raw
+
+	cacheMu.Lock()
+	if program, ok := cache[key]; ok {
+		cacheMu.Unlock()
+		return program, nil
+	}
+	cacheMu.Unlock()
+
+	program, err := Compile(raw, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMu.Lock()
+	cache[key] = program
+	cacheMu.Unlock()
+
+	return program, nil
+}
+
+type alwaysMatch struct{}
+
+func (alwaysMatch) Match(LogView) bool { return true }
+
+type substringProgram struct {
+	substr string
+}
+
+func (p substringProgram) Match(log LogView) bool {
+	return strings.Contains(log.Message, p.substr)
+}
+
+type regexProgram struct {
+	re *regexp.Regexp
+}
+
+func compileRegex(pattern string) (FilterProgram, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling regex filter %q: %w", pattern, err)
+	}
+
+	return regexProgram{re: re}, nil
+}
+
+func (p regexProgram) Match(log LogView) bool {
+	return p.re.MatchString(log.Message)
+}
+
+type fieldSource int
+
+const (
+	sourceAttribute fieldSource = iota
+	sourceTag
+)
+
+type fieldOp string
+
+const (
+	opEq fieldOp = "="
+	opNe fieldOp = "!="
+	opGe fieldOp = ">="
+	opLe fieldOp = "<="
+	opGt fieldOp = ">"
+	opLt fieldOp = "<"
+)
+
+// fieldOperatorTokens is ordered longest-token-first so ">=" is matched
+// before ">" and "!=" before "=".
+var fieldOperatorTokens = []struct {
+	token string
+	op    fieldOp
+}{
+	{">=", opGe},
+	{"<=", opLe},
+	{"!=", opNe},
+	{"==", opEq},
+	{"=", opEq},
+	{">", opGt},
+	{"<", opLt},
+}
+
+type fieldProgram struct {
+	source fieldSource
+	key    string
+	op     fieldOp
+	value  string
+}
+
+func compileField(source fieldSource, expr string) (FilterProgram, error) {
+	for _, candidate := range fieldOperatorTokens {
+		idx := strings.Index(expr, candidate.token)
+		if idx < 0 {
+			continue
+		}
+
+		return fieldProgram{
+			source: source,
+			key:    strings.TrimSpace(expr[:idx]),
+			op:     candidate.op,
+			value:  strings.TrimSpace(expr[idx+len(candidate.token):]),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("field filter %q is missing a comparison operator", expr)
+}
+
+func (p fieldProgram) Match(log LogView) bool {
+	fields := log.Attributes
+	if p.source == sourceTag {
+		fields = log.Tags
+	}
+
+	actual, ok := fields[p.key]
+	if !ok {
+		return false
+	}
+
+	switch p.op {
+	case opEq:
+		return actual == p.value
+	case opNe:
+		return actual != p.value
+	}
+
+	actualNum, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return false
+	}
+
+	wantNum, err := strconv.ParseFloat(p.value, 64)
+	if err != nil {
+		return false
+	}
+
+	switch p.op {
+	case opGe:
+		return actualNum >= wantNum
+	case opLe:
+		return actualNum <= wantNum
+	case opGt:
+		return actualNum > wantNum
+	case opLt:
+		return actualNum < wantNum
+	default:
+		return false
+	}
+}
+
+var celEnv *cel.Env
+
+func celEnvironment() (*cel.Env, error) {
+	if celEnv != nil {
+		return celEnv, nil
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("severity", cel.StringType),
+		cel.Variable("message", cel.StringType),
+		cel.Variable("attributes", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("tags", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	celEnv = env
+
+	return celEnv, nil
+}
+
+type celProgram struct {
+	program cel.Program
+}
+
+func compileCEL(expr string) (FilterProgram, error) {
+	env, err := celEnvironment()
+	if err != nil {
+		return nil, fmt.Errorf("error building cel environment: %w", err)
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("error compiling cel filter %q: %w", expr, issues.Err())
+	}
+
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("cel filter %q must evaluate to a bool, got %s", expr, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("error building cel program for filter %q: %w", expr, err)
+	}
+
+	return celProgram{program: program}, nil
+}
+
+func (p celProgram) Match(log LogView) bool {
+	out, _, err := p.program.Eval(map[string]any{
+		"severity":   log.Severity,
+		"message":    log.Message,
+		"attributes": log.Attributes,
+		"tags":       log.Tags,
+	})
+	if err != nil {
+		return false
+	}
+
+	matched, ok := out.Value().(bool)
+
+	return ok && matched
+}
@@ -0,0 +1,123 @@
+package filter
+
+import "testing"
+
+func TestCompileSubstring(t *testing.T) {
+	program, err := Compile("timeout", ModeSubstring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !program.Match(LogView{Message: "request failed: timeout"}) {
+		t.Error("expected substring match")
+	}
+
+	if program.Match(LogView{Message: "request succeeded"}) {
+		t.Error("expected no substring match")
+	}
+}
+
+func TestCompileRegex(t *testing.T) {
+	program, err := Compile("re:^ERROR.*timeout$", ModeSubstring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !program.Match(LogView{Message: "ERROR: connection timeout"}) {
+		t.Error("expected regex match")
+	}
+
+	if program.Match(LogView{Message: "INFO: connection timeout"}) {
+		t.Error("expected no regex match")
+	}
+}
+
+func TestCompileFieldAttribute(t *testing.T) {
+	program, err := Compile("attr:http.status_code>=500", ModeSubstring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	matching := LogView{Attributes: map[string]string{"http.status_code": "503"}}
+	if !program.Match(matching) {
+		t.Error("expected attribute comparison to match")
+	}
+
+	notMatching := LogView{Attributes: map[string]string{"http.status_code": "200"}}
+	if program.Match(notMatching) {
+		t.Error("expected attribute comparison to not match")
+	}
+}
+
+func TestCompileFieldTag(t *testing.T) {
+	program, err := Compile("tag:service_name=api", ModeSubstring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !program.Match(LogView{Tags: map[string]string{"service_name": "api"}}) {
+		t.Error("expected tag comparison to match")
+	}
+
+	if program.Match(LogView{Tags: map[string]string{"service_name": "worker"}}) {
+		t.Error("expected tag comparison to not match")
+	}
+}
+
+func TestCompileFieldMissingOperator(t *testing.T) {
+	if _, err := Compile("attr:http.status_code", ModeSubstring); err == nil {
+		t.Error("expected an error for a field filter with no operator")
+	}
+}
+
+func TestCompileCEL(t *testing.T) {
+	program, err := Compile(`cel:severity == "error" && message.contains("timeout")`, ModeSubstring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !program.Match(LogView{Severity: "error", Message: "connection timeout"}) {
+		t.Error("expected cel match")
+	}
+
+	if program.Match(LogView{Severity: "info", Message: "connection timeout"}) {
+		t.Error("expected no cel match")
+	}
+}
+
+func TestCompileEmptyAlwaysMatches(t *testing.T) {
+	program, err := Compile("", ModeRegex)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !program.Match(LogView{}) {
+		t.Error("expected an empty filter to always match")
+	}
+}
+
+func TestCompileCachedReusesProgram(t *testing.T) {
+	a, err := CompileCached("re:^CACHED", ModeSubstring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := CompileCached("re:^CACHED", ModeSubstring)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	aRe, ok := a.(regexProgram)
+	if !ok {
+		t.Fatalf("expected a regexProgram, got %T", a)
+	}
+
+	bRe, ok := b.(regexProgram)
+	if !ok {
+		t.Fatalf("expected a regexProgram, got %T", b)
+	}
+
+	if aRe.re != bRe.re {
+		t.Error("expected CompileCached to return the same compiled regex for an identical key")
+	}
+}
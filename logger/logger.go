@@ -0,0 +1,65 @@
+// Package logger provides the process-wide structured logger plus
+// context.Context propagation, so request-scoped fields (subscription id,
+// project/environment, resubscribe attempt, ...) attached once show up on
+// every log line emitted while handling that request.
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Stdout is the process-wide logger, configured from LOG_FORMAT and
+// LOG_LEVEL at startup.
+var Stdout = New()
+
+// New builds a logger writing to stdout. LOG_FORMAT selects "json" or
+// "text" (default "text"); LOG_LEVEL selects "debug", "info", "warn", or
+// "error" (default "info").
+func New() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(os.Getenv("LOG_LEVEL"))}
+
+	var handler slog.Handler
+
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "json") {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
+
+func parseLevel(raw string) slog.Level {
+	switch strings.ToLower(raw) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type contextKey struct{}
+
+// FromContext returns the logger attached to ctx by WithContext, or Stdout
+// if ctx carries none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if log, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return log
+	}
+
+	return Stdout
+}
+
+// WithContext attaches log to ctx, returning the derived context alongside
+// log itself so callers can use either without a round-trip through
+// FromContext.
+func WithContext(ctx context.Context, log *slog.Logger) (context.Context, *slog.Logger) {
+	return context.WithValue(ctx, contextKey{}, log), log
+}
@@ -0,0 +1,206 @@
+// Package otlp ships EnvironmentLogs to any OpenTelemetry-compatible collector
+// via OTLP/HTTP, so logs can reach Datadog, Honeycomb, Grafana, etc. without a
+// bespoke integration per vendor.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ferretcode/locomotive/config"
+	"github.com/ferretcode/locomotive/railway"
+)
+
+// Exporter sends batches of logs to an OTLP/HTTP collector.
+type Exporter struct {
+	endpoint           string
+	headers            map[string]string
+	resourceAttributes map[string]string
+
+	httpClient *http.Client
+}
+
+// NewExporter builds an Exporter from config. It returns a nil Exporter (and a
+// nil error) when OTLP_ENDPOINT isn't set, so callers can skip the sink entirely.
+func NewExporter(cfg *config.Config) (*Exporter, error) {
+	if cfg.OtlpEndpoint == "" {
+		return nil, nil
+	}
+
+	if cfg.OtlpProtocol == "grpc" {
+		return nil, errors.New("OTLP_PROTOCOL=grpc is not yet supported, use OTLP_PROTOCOL=http")
+	}
+
+	return &Exporter{
+		endpoint:           cfg.OtlpEndpoint,
+		headers:            cfg.OtlpHeaders,
+		resourceAttributes: cfg.OtlpResourceAttributes,
+		httpClient:         &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// logsData mirrors the relevant subset of the OTLP logs/v1 JSON payload.
+type logsData struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano   string     `json:"timeUnixNano"`
+	SeverityNumber int        `json:"severityNumber"`
+	SeverityText   string     `json:"severityText"`
+	Body           anyValue   `json:"body"`
+	Attributes     []keyValue `json:"attributes"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// Export maps a batch of EnvironmentLogs into an OTLP LogsData payload,
+// grouped by a single resource derived from cfg.OtlpResourceAttributes plus
+// the project/environment/service tags of the batch's first log, and POSTs it
+// to the configured collector. Logs from other services in the same batch are
+// reported under that first log's resource.
+//
+// Export intentionally sends each batch it's given immediately rather than
+// buffering across calls for a ReportStatusEvery-sized window: sinks.Registry
+// runs exactly one worker goroutine per sink, blocking on Send until it
+// returns before pulling that sink's next queued batch, so there is never
+// more than one batch outstanding to coalesce. Holding logs here to wait out
+// an interval would only add latency and back up this sink's queue - risking
+// Registry's drop-on-full-queue path under sustained volume - without ever
+// actually combining multiple batches into one request. ReportStatusEvery
+// already governs how often Registry reports this sink's metrics; the batch
+// size Export sees is whatever Dispatch handed it.
+func (e *Exporter) Export(ctx context.Context, logs []railway.EnvironmentLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	records := make([]logRecord, 0, len(logs))
+
+	for i := range logs {
+		severityNumber, severityText := mapSeverity(logs[i].Severity)
+
+		attributes := make([]keyValue, 0, len(logs[i].Attributes)+1)
+
+		for _, attribute := range logs[i].Attributes {
+			attributes = append(attributes, keyValue{Key: attribute.Key, Value: anyValue{StringValue: attribute.Value}})
+		}
+
+		attributes = append(attributes, keyValue{Key: "deployment.instance.id", Value: anyValue{StringValue: logs[i].Tags.DeploymentInstanceID}})
+
+		records = append(records, logRecord{
+			TimeUnixNano:   strconv.FormatInt(logs[i].Timestamp.UnixNano(), 10),
+			SeverityNumber: severityNumber,
+			SeverityText:   severityText,
+			Body:           anyValue{StringValue: logs[i].Message},
+			Attributes:     attributes,
+		})
+	}
+
+	payload := logsData{
+		ResourceLogs: []resourceLogs{
+			{
+				Resource: resource{Attributes: e.buildResourceAttributes(logs[0])},
+				ScopeLogs: []scopeLogs{
+					{
+						Scope:      scope{Name: "locomotive"},
+						LogRecords: records,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(&payload)
+	if err != nil {
+		return fmt.Errorf("error marshalling otlp payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building otlp request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending otlp request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("otlp collector returned status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func (e *Exporter) buildResourceAttributes(log railway.EnvironmentLog) []keyValue {
+	attributes := []keyValue{
+		{Key: "service.name", Value: anyValue{StringValue: log.Tags.ServiceName}},
+		{Key: "service.namespace", Value: anyValue{StringValue: log.Tags.ProjectName}},
+		{Key: "deployment.environment", Value: anyValue{StringValue: log.Tags.EnvironmentName}},
+	}
+
+	for key, value := range e.resourceAttributes {
+		attributes = append(attributes, keyValue{Key: key, Value: anyValue{StringValue: value}})
+	}
+
+	return attributes
+}
+
+// mapSeverity translates Railway's severity strings into the OTLP
+// SeverityNumber/SeverityText pair (see the OTel logs data model).
+func mapSeverity(severity string) (int, string) {
+	switch severity {
+	case "debug":
+		return 5, "DEBUG"
+	case "info":
+		return 9, "INFO"
+	case "warn", "warning":
+		return 13, "WARN"
+	case "error":
+		return 17, "ERROR"
+	case "fatal", "panic":
+		return 21, "FATAL"
+	default:
+		return 0, "UNSPECIFIED"
+	}
+}
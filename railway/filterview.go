@@ -0,0 +1,28 @@
+package railway
+
+import "github.com/ferretcode/locomotive/filter"
+
+// BuildLogView converts an EnvironmentLog into the typed view that
+// field-scoped (attr:/tag:) and CEL content filters evaluate against.
+func BuildLogView(log EnvironmentLog) filter.LogView {
+	attributes := make(map[string]string, len(log.Attributes))
+
+	for _, attribute := range log.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+
+	return filter.LogView{
+		Severity:   log.Severity,
+		Message:    log.Message,
+		Attributes: attributes,
+		Tags: map[string]string{
+			"service_id":             log.Tags.ServiceID,
+			"service_name":           log.Tags.ServiceName,
+			"environment_id":         log.Tags.EnvironmentID,
+			"environment_name":       log.Tags.EnvironmentName,
+			"project_id":             log.Tags.ProjectID,
+			"project_name":           log.Tags.ProjectName,
+			"deployment_instance_id": log.Tags.DeploymentInstanceID,
+		},
+	}
+}
@@ -2,10 +2,10 @@ package railway
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 
+	"github.com/ferretcode/locomotive/filter"
 	"github.com/ferretcode/locomotive/util"
 )
 
@@ -22,11 +22,7 @@ func AttributesHasKeys(attributes []Attributes, keys []string) (string, bool) {
 	return "", false
 }
 
-func FilterLogs(logs []EnvironmentLog, wantedLevel []string, contentFilter string) []EnvironmentLog {
-	if len(wantedLevel) == 0 && contentFilter == "" {
-		return logs
-	}
-
+func FilterLogs(logs []EnvironmentLog, wantedLevel []string, contentFilter filter.FilterProgram) []EnvironmentLog {
 	filteredLogs := []EnvironmentLog{}
 
 	for i := range logs {
@@ -34,9 +30,7 @@ func FilterLogs(logs []EnvironmentLog, wantedLevel []string, contentFilter strin
 			continue
 		}
 
-		// Convert log to JSON string for content filtering
-		logJSON, _ := json.Marshal(logs[i])
-		if !util.MatchesContentFilter(contentFilter, string(logJSON)) {
+		if contentFilter != nil && !contentFilter.Match(BuildLogView(logs[i])) {
 			continue
 		}
 
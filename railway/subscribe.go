@@ -8,15 +8,22 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/coder/websocket"
 	"github.com/ferretcode/locomotive/config"
 	"github.com/ferretcode/locomotive/logger"
 	"github.com/ferretcode/locomotive/util"
+	"github.com/ferretcode/locomotive/wal"
 	"github.com/google/uuid"
 )
 
+// maxResubscribeLookback caps how far back BeforeDate can reach even when the
+// WAL has been retaining logs longer than that, since Railway only keeps a
+// bounded amount of log history to serve anyway.
+const maxResubscribeLookback = 24 * time.Hour
+
 func (g *GraphQLClient) buildMetadataMap(ctx context.Context, cfg *config.Config) (map[string]string, error) {
 	if g.client == nil {
 		return nil, errors.New("client is nil")
@@ -89,7 +96,7 @@ var (
 	connectionAck  = []byte(`{"type":"connection_ack"}`)
 )
 
-func (g *GraphQLClient) createSubscription(ctx context.Context, cfg *config.Config) (*websocket.Conn, error) {
+func (g *GraphQLClient) createSubscription(ctx context.Context, walWriter *wal.Writer, cfg *config.Config) (*websocket.Conn, context.Context, []string, error) {
 	var services []string
 
 	// If Train services are specified, use them. Otherwise, auto-discover services.
@@ -99,12 +106,12 @@ func (g *GraphQLClient) createSubscription(ctx context.Context, cfg *config.Conf
 		// Auto-discover all services in the environment
 		autoServices, err := g.GetAllServicesInEnvironment(ctx, cfg.ProjectId, cfg.EnvironmentId)
 		if err != nil {
-			return nil, fmt.Errorf("error auto-discovering services: %w", err)
+			return nil, nil, nil, fmt.Errorf("error auto-discovering services: %w", err)
 		}
 		services = autoServices
-		logger.Stdout.Info("auto-discovered services", slog.Any("services", services), slog.Int("count", len(services)))
+		logger.FromContext(ctx).Info("auto-discovered services", slog.Any("services", services), slog.Int("count", len(services)))
 	} else {
-		return nil, errors.New("either TRAIN services must be specified or RAILWAY_PROJECT_ID must be provided for auto-discovery")
+		return nil, nil, nil, errors.New("either TRAIN services must be specified or RAILWAY_PROJECT_ID must be provided for auto-discovery")
 	}
 
 	payload := &payload{
@@ -114,22 +121,26 @@ func (g *GraphQLClient) createSubscription(ctx context.Context, cfg *config.Conf
 			Filter:        buildServiceFilter(services),
 
 			// needed for seamless subscription resuming
-			BeforeDate:  time.Now().UTC().Add(-5 * time.Minute).Format(time.RFC3339Nano),
+			BeforeDate:  resubscribeBeforeDate(walWriter).Format(time.RFC3339Nano),
 			BeforeLimit: 500,
 		},
 	}
 
+	subscriptionId := uuid.Must(uuid.NewUUID()).String()
+
 	subPayload := operationMessage{
-		Id:      uuid.Must(uuid.NewUUID()).String(),
+		Id:      subscriptionId,
 		Type:    "subscribe",
 		Payload: *payload,
 	}
 
 	payloadBytes, err := json.Marshal(&subPayload)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
+	ctx, log := logger.WithContext(ctx, logger.FromContext(ctx).With(slog.String("subscription_id", subscriptionId)))
+
 	opts := &websocket.DialOptions{
 		HTTPHeader: http.Header{
 			"Authorization": []string{"Bearer " + g.AuthToken},
@@ -143,58 +154,213 @@ func (g *GraphQLClient) createSubscription(ctx context.Context, cfg *config.Conf
 
 	c, _, err := websocket.Dial(ctxTimeout, g.BaseSubscriptionURL, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	c.SetReadLimit(-1)
 
 	if err := c.Write(ctx, websocket.MessageText, connectionInit); err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	_, ackMessage, err := c.Read(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	if !bytes.Equal(ackMessage, connectionAck) {
-		return nil, errors.New("did not receive connection ack from server")
+		return nil, nil, nil, errors.New("did not receive connection ack from server")
 	}
 
 	if err := c.Write(ctx, websocket.MessageText, payloadBytes); err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	return c, nil
+	log.Debug("subscribed to logs")
+
+	return c, ctx, services, nil
+}
+
+// subscriptionState is the view of the current websocket and its auto-
+// discovered service list shared between SubscribeToLogs and
+// watchServiceDiscovery, which run concurrently.
+type subscriptionState struct {
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	services []string
+}
+
+func (s *subscriptionState) set(conn *websocket.Conn, services []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conn = conn
+	s.services = services
+}
+
+func (s *subscriptionState) snapshot() (*websocket.Conn, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conn, s.services
+}
+
+// watchServiceDiscovery polls GetAllServicesInEnvironment on
+// cfg.ServiceDiscoveryInterval and closes the current websocket the moment
+// the service list diverges from the one the subscription was opened with.
+// SubscribeToLogs' own resubscribe loop picks up the change: closing the
+// conn surfaces a read error, and the next createSubscription call
+// auto-discovers services again, now including the entered/left change.
+func (g *GraphQLClient) watchServiceDiscovery(ctx context.Context, cfg *config.Config, state *subscriptionState) {
+	ticker := time.NewTicker(cfg.ServiceDiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			latest, err := g.GetAllServicesInEnvironment(ctx, cfg.ProjectId, cfg.EnvironmentId)
+			if err != nil {
+				logger.FromContext(ctx).Warn("error polling for service discovery", slog.Any("error", err))
+				continue
+			}
+
+			conn, current := state.snapshot()
+			if conn == nil {
+				continue
+			}
+
+			entered, left := diffServices(current, latest)
+			if len(entered) == 0 && len(left) == 0 {
+				continue
+			}
+
+			log := logger.FromContext(ctx)
+
+			if len(entered) > 0 {
+				log.Info("service entered the train", slog.Any("services", entered))
+			}
+
+			if len(left) > 0 {
+				log.Info("service left the train", slog.Any("services", left))
+			}
+
+			safeConnCloseNow(conn)
+		}
+	}
 }
 
-func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, logTrack chan<- []EnvironmentLog, cfg *config.Config) error {
+// diffServices reports which service ids are present in latest but not
+// current (entered) and present in current but not latest (left).
+func diffServices(current, latest []string) (entered, left []string) {
+	currentSet := make(map[string]struct{}, len(current))
+	for _, id := range current {
+		currentSet[id] = struct{}{}
+	}
+
+	latestSet := make(map[string]struct{}, len(latest))
+	for _, id := range latest {
+		latestSet[id] = struct{}{}
+	}
+
+	for _, id := range latest {
+		if _, ok := currentSet[id]; !ok {
+			entered = append(entered, id)
+		}
+	}
+
+	for _, id := range current {
+		if _, ok := latestSet[id]; !ok {
+			left = append(left, id)
+		}
+	}
+
+	return entered, left
+}
+
+// resubscribeBeforeDate picks how far back to ask Railway to replay on
+// (re)subscribe. With a WAL configured we can safely go all the way back to
+// the oldest entry it still retains, since anything older has either been
+// acked by every sink or outlived WAL_RETENTION; without one we fall back to a
+// conservative 5 minutes.
+func resubscribeBeforeDate(walWriter *wal.Writer) time.Time {
+	fallback := time.Now().UTC().Add(-5 * time.Minute)
+
+	if walWriter == nil {
+		return fallback
+	}
+
+	oldest, ok := walWriter.OldestTimestamp()
+	if !ok {
+		return fallback
+	}
+
+	if cutoff := time.Now().UTC().Add(-maxResubscribeLookback); oldest.Before(cutoff) {
+		return cutoff
+	}
+
+	return oldest
+}
+
+// dispatch hands a filtered batch of logs off to whatever is consuming them,
+// e.g. a sinks.Registry's Dispatch method. ctx carries the subscription's
+// logger fields so sinks can log with the same context. walOffset is the
+// highest WAL offset the batch was appended at; walOffsetOk is false when
+// there's no WAL configured.
+type dispatch func(ctx context.Context, logs []EnvironmentLog, walOffset uint64, walOffsetOk bool)
+
+func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, dispatchLogs dispatch, walWriter *wal.Writer, cfg *config.Config) error {
 	metadataMap, err := g.buildMetadataMap(ctx, cfg)
 	if err != nil {
 		return fmt.Errorf("error building metadata map: %w", err)
 	}
 
-	conn, err := g.createSubscription(ctx, cfg)
+	baseCtx, _ := logger.WithContext(ctx, logger.FromContext(ctx).With(
+		slog.String("project_id", cfg.ProjectId),
+		slog.String("environment_id", cfg.EnvironmentId),
+	))
+
+	conn, ctx, services, err := g.createSubscription(baseCtx, walWriter, cfg)
 	if err != nil {
 		return err
 	}
 
 	defer conn.CloseNow()
 
+	state := &subscriptionState{}
+	state.set(conn, services)
+
+	// GetConfig rejects AUTO_DISCOVER_SERVICES together with TRAIN, so this
+	// flag alone tells us services come from auto-discovery, not a static
+	// TRAIN list.
+	if cfg.AutoDiscoverServices {
+		watchCtx, cancelWatch := context.WithCancel(baseCtx)
+		defer cancelWatch()
+
+		go g.watchServiceDiscovery(watchCtx, cfg, state)
+	}
+
 	LogTime := time.Now().UTC()
+	resubscribeAttempt := 0
 
 	for {
+		log := logger.FromContext(ctx)
+
 		_, logPayload, err := safeConnRead(conn, ctx)
 		if err != nil {
-			logger.Stdout.Debug("resubscribing", slog.Any("reason", err))
+			resubscribeAttempt++
+			log.Debug("resubscribing", slog.Any("reason", err), slog.Int("attempt", resubscribeAttempt), slog.Time("last_log_time", LogTime))
 
 			safeConnCloseNow(conn)
 
-			conn, err = g.createSubscription(ctx, cfg)
+			conn, ctx, services, err = g.createSubscription(baseCtx, walWriter, cfg)
 			if err != nil {
 				return err
 			}
 
+			state.set(conn, services)
+
 			continue
 		}
 
@@ -205,18 +371,23 @@ func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, logTrack chan<- []E
 		}
 
 		if logs.Type != TypeNext {
-			logger.Stdout.Debug("resubscribing", slog.String("reason", fmt.Sprintf("log type not next: %s", logs.Type)))
+			resubscribeAttempt++
+			log.Debug("resubscribing", slog.String("reason", fmt.Sprintf("log type not next: %s", logs.Type)), slog.Int("attempt", resubscribeAttempt), slog.Time("last_log_time", LogTime))
 
 			safeConnCloseNow(conn)
 
-			conn, err = g.createSubscription(ctx, cfg)
+			conn, ctx, services, err = g.createSubscription(baseCtx, walWriter, cfg)
 			if err != nil {
 				return err
 			}
 
+			state.set(conn, services)
+
 			continue
 		}
 
+		resubscribeAttempt = 0
+
 		filteredLogs := []EnvironmentLog{}
 
 		for i := range logs.Payload.Data.EnvironmentLogs {
@@ -228,25 +399,19 @@ func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, logTrack chan<- []E
 
 			// skip container logs, container logs don't have deployment instance ids
 			if logs.Payload.Data.EnvironmentLogs[i].Tags.DeploymentInstanceID == "" {
-				logger.Stdout.Debug("skipping container log message")
+				log.Debug("skipping container log message")
 				continue
 			}
 
 			// on first subscription skip logs if they where logged before the first subscription, on resubscription skip logs if they where already processed
 			if logs.Payload.Data.EnvironmentLogs[i].Timestamp.Before(LogTime) || LogTime == logs.Payload.Data.EnvironmentLogs[i].Timestamp {
-				// logger.Stdout.Debug("skipping stale log message")
+				// log.Debug("skipping stale log message")
 				continue
 			}
 
 			// skip logs that don't match our desired global filter(s)
 			if !util.IsWantedLevel(cfg.LogsFilterGlobal, logs.Payload.Data.EnvironmentLogs[i].Severity) {
-				logger.Stdout.Debug("skipping undesired global log level", slog.String("level", logs.Payload.Data.EnvironmentLogs[i].Severity), slog.Any("wanted", cfg.LogsFilterGlobal))
-				continue
-			}
-
-			// skip logs that don't match our desired global content filter(s)
-			if !util.MatchesContentFilter(cfg.LogsContentFilterGlobal, logs.Payload.Data.EnvironmentLogs[i].Message) {
-				logger.Stdout.Debug("skipping undesired global log content", slog.String("content", logs.Payload.Data.EnvironmentLogs[i].Message), slog.String("filter", cfg.LogsContentFilterGlobal))
+				log.Debug("skipping undesired global log level", slog.String("level", logs.Payload.Data.EnvironmentLogs[i].Severity), slog.Any("wanted", cfg.LogsFilterGlobal))
 				continue
 			}
 
@@ -254,7 +419,7 @@ func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, logTrack chan<- []E
 
 			serviceName, ok := metadataMap[logs.Payload.Data.EnvironmentLogs[i].Tags.ServiceID]
 			if !ok {
-				logger.Stdout.Warn("service name could not be found")
+				log.Warn("service name could not be found")
 				serviceName = "undefined"
 			}
 
@@ -262,7 +427,7 @@ func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, logTrack chan<- []E
 
 			environmentName, ok := metadataMap[logs.Payload.Data.EnvironmentLogs[i].Tags.EnvironmentID]
 			if !ok {
-				logger.Stdout.Warn("environment name could not be found")
+				log.Warn("environment name could not be found")
 				environmentName = "undefined"
 			}
 
@@ -270,12 +435,21 @@ func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, logTrack chan<- []E
 
 			projectName, ok := metadataMap[logs.Payload.Data.EnvironmentLogs[i].Tags.ProjectID]
 			if !ok {
-				logger.Stdout.Warn("project name could not be found")
+				log.Warn("project name could not be found")
 				projectName = "undefined"
 			}
 
 			logs.Payload.Data.EnvironmentLogs[i].Tags.ProjectName = projectName
 
+			// skip logs that don't match our desired global content filter(s); this
+			// runs after the metadata lookups above so tag:service_name/
+			// environment_name/project_name filters can see the resolved names,
+			// not just the raw ids that come straight off the wire
+			if !cfg.LogsContentFilterProgramGlobal.Match(BuildLogView(logs.Payload.Data.EnvironmentLogs[i])) {
+				log.Debug("skipping undesired global log content", slog.String("content", logs.Payload.Data.EnvironmentLogs[i].Message), slog.String("filter", cfg.LogsContentFilterGlobal))
+				continue
+			}
+
 			filteredLogs = append(filteredLogs, logs.Payload.Data.EnvironmentLogs[i])
 		}
 
@@ -283,7 +457,29 @@ func (g *GraphQLClient) SubscribeToLogs(ctx context.Context, logTrack chan<- []E
 			continue
 		}
 
-		logTrack <- filteredLogs
+		var walOffset uint64
+		walOffsetOk := false
+
+		if walWriter != nil {
+			for i := range filteredLogs {
+				data, err := json.Marshal(&filteredLogs[i])
+				if err != nil {
+					log.Warn("error marshalling log for wal", slog.Any("error", err))
+					continue
+				}
+
+				offset, err := walWriter.Append(data)
+				if err != nil {
+					log.Warn("error appending log to wal", slog.Any("error", err))
+					continue
+				}
+
+				walOffset = offset
+				walOffsetOk = true
+			}
+		}
+
+		dispatchLogs(ctx, filteredLogs, walOffset, walOffsetOk)
 	}
 }
 
@@ -0,0 +1,113 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ferretcode/locomotive/config"
+	"github.com/ferretcode/locomotive/railway"
+)
+
+// discordMaxContentLength is Discord's hard cap on a message's "content"
+// field; batches are truncated to it rather than split across requests.
+const discordMaxContentLength = 2000
+
+// DiscordSink forwards batches of logs to a Discord webhook, one chat
+// message per batch.
+type DiscordSink struct {
+	webhookUrl string
+	prettyJson bool
+	filter     SinkFilter
+
+	httpClient *http.Client
+}
+
+// NewDiscordSink builds a DiscordSink from cfg. It returns nil when
+// DISCORD_WEBHOOK_URL isn't set, so callers can skip the sink entirely.
+func NewDiscordSink(cfg *config.Config) *DiscordSink {
+	if cfg.DiscordWebhookUrl == "" {
+		return nil
+	}
+
+	return &DiscordSink{
+		webhookUrl: cfg.DiscordWebhookUrl,
+		prettyJson: cfg.DiscordPrettyJson,
+		filter: SinkFilter{
+			Levels:  cfg.LogsFilterDiscord,
+			Content: cfg.LogsContentFilterProgramDiscord,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *DiscordSink) Name() string { return "discord" }
+
+func (s *DiscordSink) Filter() SinkFilter { return s.filter }
+
+func (s *DiscordSink) Send(ctx context.Context, logs []railway.EnvironmentLog) error {
+	content, err := formatDiscordContent(logs, s.prettyJson)
+	if err != nil {
+		return fmt.Errorf("error formatting discord message: %w", err)
+	}
+
+	body, err := json.Marshal(&struct {
+		Content string `json:"content"`
+	}{Content: content})
+	if err != nil {
+		return fmt.Errorf("error marshalling discord payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building discord request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending discord request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("discord webhook returned status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// formatDiscordContent renders a batch as either one fenced JSON code block
+// per log (prettyJson) or a compact "LEVEL service: message" line per log,
+// truncated to discordMaxContentLength.
+func formatDiscordContent(logs []railway.EnvironmentLog, prettyJson bool) (string, error) {
+	var builder strings.Builder
+
+	for i := range logs {
+		if prettyJson {
+			data, err := json.MarshalIndent(logs[i], "", "  ")
+			if err != nil {
+				return "", err
+			}
+
+			builder.WriteString("```json\n")
+			builder.Write(data)
+			builder.WriteString("\n```\n")
+		} else {
+			builder.WriteString(fmt.Sprintf("**%s** `%s`: %s\n", strings.ToUpper(logs[i].Severity), logs[i].Tags.ServiceName, logs[i].Message))
+		}
+	}
+
+	content := builder.String()
+	if len(content) > discordMaxContentLength {
+		content = content[:discordMaxContentLength]
+	}
+
+	return content, nil
+}
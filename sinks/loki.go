@@ -0,0 +1,126 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ferretcode/locomotive/config"
+	"github.com/ferretcode/locomotive/railway"
+)
+
+// LokiSink forwards batches of logs to a Loki push API endpoint.
+type LokiSink struct {
+	endpoint string
+	filter   SinkFilter
+
+	httpClient *http.Client
+}
+
+// NewLokiSink builds a LokiSink from cfg. It returns nil when
+// LOKI_INGEST_URL isn't set, so callers can skip the sink entirely.
+func NewLokiSink(cfg *config.Config) *LokiSink {
+	if cfg.LokiIngestUrl == "" {
+		return nil
+	}
+
+	return &LokiSink{
+		endpoint: cfg.LokiIngestUrl,
+		filter: SinkFilter{
+			Levels:  cfg.LogsFilterLoki,
+			Content: cfg.LogsContentFilterProgramLoki,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *LokiSink) Name() string { return "loki" }
+
+func (s *LokiSink) Filter() SinkFilter { return s.filter }
+
+// lokiPushRequest mirrors the relevant subset of Loki's /loki/api/v1/push
+// JSON payload.
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *LokiSink) Send(ctx context.Context, logs []railway.EnvironmentLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	streams := groupLokiStreams(logs)
+
+	body, err := json.Marshal(&lokiPushRequest{Streams: streams})
+	if err != nil {
+		return fmt.Errorf("error marshalling loki payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building loki request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending loki request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("loki returned status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// groupLokiStreams buckets logs by (project, environment, service, severity)
+// so stream labels stay low-cardinality instead of one stream per log.
+func groupLokiStreams(logs []railway.EnvironmentLog) []lokiStream {
+	byLabels := make(map[string]*lokiStream)
+	order := make([]string, 0)
+
+	for i := range logs {
+		tags := logs[i].Tags
+
+		labels := map[string]string{
+			"project_name":     tags.ProjectName,
+			"environment_name": tags.EnvironmentName,
+			"service_name":     tags.ServiceName,
+			"level":            logs[i].Severity,
+		}
+
+		key := labels["project_name"] + "/" + labels["environment_name"] + "/" + labels["service_name"] + "/" + labels["level"]
+
+		stream, ok := byLabels[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			byLabels[key] = stream
+			order = append(order, key)
+		}
+
+		stream.Values = append(stream.Values, [2]string{
+			strconv.FormatInt(logs[i].Timestamp.UnixNano(), 10),
+			logs[i].Message,
+		})
+	}
+
+	streams := make([]lokiStream, 0, len(order))
+	for _, key := range order {
+		streams = append(streams, *byLabels[key])
+	}
+
+	return streams
+}
@@ -0,0 +1,35 @@
+package sinks
+
+import (
+	"context"
+
+	"github.com/ferretcode/locomotive/config"
+	"github.com/ferretcode/locomotive/otlp"
+	"github.com/ferretcode/locomotive/railway"
+)
+
+// OtlpSink adapts an otlp.Exporter to the Sink interface.
+type OtlpSink struct {
+	exporter *otlp.Exporter
+	filter   SinkFilter
+}
+
+// NewOtlpSink wraps exporter as a Sink, applying the OTLP-specific filters
+// from cfg.
+func NewOtlpSink(exporter *otlp.Exporter, cfg *config.Config) *OtlpSink {
+	return &OtlpSink{
+		exporter: exporter,
+		filter: SinkFilter{
+			Levels:  cfg.LogsFilterOtlp,
+			Content: cfg.LogsContentFilterProgramOtlp,
+		},
+	}
+}
+
+func (s *OtlpSink) Name() string { return "otlp" }
+
+func (s *OtlpSink) Filter() SinkFilter { return s.filter }
+
+func (s *OtlpSink) Send(ctx context.Context, logs []railway.EnvironmentLog) error {
+	return s.exporter.Export(ctx, logs)
+}
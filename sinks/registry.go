@@ -0,0 +1,294 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/ferretcode/locomotive/logger"
+	"github.com/ferretcode/locomotive/railway"
+	"github.com/ferretcode/locomotive/util"
+	"github.com/ferretcode/locomotive/wal"
+	"github.com/sethvargo/go-retry"
+)
+
+// queueSize bounds how many pending batches a single sink can fall behind by
+// before Dispatch starts dropping batches for it.
+const queueSize = 64
+
+// sendRetryBackoffCap bounds the interval *between* retries of a batch, not
+// the number of attempts: runWorker processes one sink's queue sequentially,
+// so a batch is retried until it succeeds or ctx is canceled. Giving up
+// early would let the next batch's successful Commit silently advance the
+// acked offset past this one, losing it for good despite the WAL still
+// holding it — exactly what the WAL exists to prevent.
+const sendRetryBackoffCap = 30 * time.Second
+
+// metrics tracks the counters a Registry reports every ReportStatusEvery tick.
+type metrics struct {
+	batchesSent atomic.Int64
+	bytesSent   atomic.Int64
+	errors      atomic.Int64
+	retries     atomic.Int64
+	dropped     atomic.Int64
+}
+
+// sinkBatch is what's queued for a sink worker: a filtered batch plus the
+// context it was dispatched with, so Send and its logging inherit the
+// originating subscription's logger fields. walOffset is the highest WAL
+// offset covered by the batch; walOffsetOk is false when the batch wasn't
+// WAL-backed (WAL_DIR unset).
+type sinkBatch struct {
+	ctx         context.Context
+	logs        []railway.EnvironmentLog
+	walOffset   uint64
+	walOffsetOk bool
+}
+
+type registeredSink struct {
+	sink    Sink
+	queue   chan sinkBatch
+	metrics metrics
+}
+
+// Registry fans filtered log batches out to every registered Sink
+// concurrently, isolating slow or failing sinks from healthy ones.
+type Registry struct {
+	sinks       []*registeredSink
+	offsetStore *wal.OffsetStore
+}
+
+// NewRegistry builds an empty Registry. Sinks are added with Register before
+// Start is called.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a sink to the registry. It must be called before Start.
+func (r *Registry) Register(sink Sink) {
+	r.sinks = append(r.sinks, &registeredSink{
+		sink:  sink,
+		queue: make(chan sinkBatch, queueSize),
+	})
+}
+
+// UseWAL enables offset-tracked delivery: once set, every sink commits the
+// WAL offset of a batch to store after successfully sending it, so Replay
+// and the compactor know what each sink has durably processed.
+func (r *Registry) UseWAL(store *wal.OffsetStore) {
+	r.offsetStore = store
+}
+
+// Replay re-delivers, to each registered sink, whatever WAL entries that
+// sink hasn't acked yet per offsetStore, so a sink that was down across a
+// restart catches back up before live dispatch resumes. Call it once, after
+// UseWAL and before Start. It's a no-op if UseWAL hasn't been called.
+func (r *Registry) Replay(ctx context.Context, writer *wal.Writer) error {
+	if r.offsetStore == nil {
+		return nil
+	}
+
+	for _, rs := range r.sinks {
+		from, _ := r.offsetStore.Get(rs.sink.Name())
+
+		err := writer.Replay(from, func(offset uint64, data []byte) error {
+			var replayed railway.EnvironmentLog
+
+			if err := json.Unmarshal(data, &replayed); err != nil {
+				logger.Stdout.Warn("error unmarshalling wal entry during replay", slog.Any("error", err))
+				return nil
+			}
+
+			filter := rs.sink.Filter()
+
+			if !util.IsWantedLevel(filter.Levels, replayed.Severity) {
+				return nil
+			}
+
+			if filter.Content != nil && !filter.Content.Match(railway.BuildLogView(replayed)) {
+				return nil
+			}
+
+			if err := rs.sink.Send(ctx, []railway.EnvironmentLog{replayed}); err != nil {
+				return err
+			}
+
+			return r.offsetStore.Commit(rs.sink.Name(), offset)
+		})
+		if err != nil {
+			return fmt.Errorf("error replaying wal for sink %s: %w", rs.sink.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// ReplayFrom re-sends every WAL entry after from to every registered sink,
+// through the same Dispatch path live logs use. This backs REPLAY_FROM: an
+// operator-triggered backfill, independent of any sink's own acked offset.
+func (r *Registry) ReplayFrom(ctx context.Context, writer *wal.Writer, from uint64) error {
+	return writer.Replay(from, func(offset uint64, data []byte) error {
+		var replayed railway.EnvironmentLog
+
+		if err := json.Unmarshal(data, &replayed); err != nil {
+			logger.Stdout.Warn("error unmarshalling wal entry during replay", slog.Any("error", err))
+			return nil
+		}
+
+		r.Dispatch(ctx, []railway.EnvironmentLog{replayed}, offset, true)
+
+		return nil
+	})
+}
+
+// StartCompactor launches the WAL compactor alongside the registry's sink
+// workers, trimming segments once every registered sink has acked past
+// them. It's a no-op if UseWAL hasn't been called.
+func (r *Registry) StartCompactor(ctx context.Context, writer *wal.Writer, retention time.Duration, interval time.Duration) {
+	if r.offsetStore == nil {
+		return
+	}
+
+	sinkNames := make([]string, len(r.sinks))
+	for i, rs := range r.sinks {
+		sinkNames[i] = rs.sink.Name()
+	}
+
+	go wal.RunCompactor(ctx, writer, r.offsetStore, sinkNames, retention, interval)
+}
+
+// Start spawns one worker per registered sink plus a status ticker that logs
+// per-sink metrics every reportEvery. It returns immediately; the workers and
+// the ticker stop once ctx is canceled.
+func (r *Registry) Start(ctx context.Context, reportEvery time.Duration) {
+	for _, rs := range r.sinks {
+		go r.runWorker(ctx, rs)
+	}
+
+	go r.reportStatus(ctx, reportEvery)
+}
+
+// Dispatch filters logs per-sink and enqueues the resulting batch for async
+// delivery. A sink whose queue is full has the batch dropped for it rather
+// than blocking delivery to the other sinks. ctx is carried through to Send
+// so sinks inherit the originating subscription's logger fields. walOffset
+// is the highest WAL offset covered by logs; walOffsetOk is false when the
+// batch wasn't WAL-backed (WAL_DIR unset).
+func (r *Registry) Dispatch(ctx context.Context, logs []railway.EnvironmentLog, walOffset uint64, walOffsetOk bool) {
+	for _, rs := range r.sinks {
+		filter := rs.sink.Filter()
+
+		filtered := make([]railway.EnvironmentLog, 0, len(logs))
+
+		for i := range logs {
+			if !util.IsWantedLevel(filter.Levels, logs[i].Severity) {
+				continue
+			}
+
+			if filter.Content != nil && !filter.Content.Match(railway.BuildLogView(logs[i])) {
+				continue
+			}
+
+			filtered = append(filtered, logs[i])
+		}
+
+		if len(filtered) == 0 {
+			continue
+		}
+
+		select {
+		case rs.queue <- sinkBatch{ctx: ctx, logs: filtered, walOffset: walOffset, walOffsetOk: walOffsetOk}:
+		default:
+			rs.metrics.dropped.Add(1)
+			logger.FromContext(ctx).Warn("sink queue is full, dropping batch", slog.String("sink", rs.sink.Name()), slog.Int("batch_size", len(filtered)))
+		}
+	}
+}
+
+func (r *Registry) runWorker(ctx context.Context, rs *registeredSink) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case batch := <-rs.queue:
+			r.send(batch.ctx, rs, batch)
+		}
+	}
+}
+
+// send delivers batch, retrying with backoff until it succeeds or ctx is
+// canceled (process shutdown). It never gives up and moves on while batch
+// is unacked: runWorker calls send synchronously for one sink's queue, so
+// blocking here also blocks that sink from reaching any later batch, which
+// is what keeps Commit calls in offset order. A permanently broken sink
+// backs its own queue up instead, and Dispatch starts dropping new batches
+// for it once the queue is full - a visible, metriced failure mode, rather
+// than a silent gap in what was acked.
+func (r *Registry) send(ctx context.Context, rs *registeredSink, batch sinkBatch) {
+	backoff := retry.NewExponential(200 * time.Millisecond)
+	backoff = retry.WithCappedDuration(sendRetryBackoffCap, backoff)
+	backoff = retry.WithJitterPercent(10, backoff)
+
+	err := retry.Do(ctx, backoff, func(ctx context.Context) error {
+		if err := rs.sink.Send(ctx, batch.logs); err != nil {
+			rs.metrics.retries.Add(1)
+			logger.FromContext(ctx).Debug("sink send failed, retrying", slog.String("sink", rs.sink.Name()), slog.Any("error", err))
+			return retry.RetryableError(err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		// Only reachable via ctx cancellation (shutdown); batch was never
+		// acked, so its offset must never be committed.
+		rs.metrics.errors.Add(1)
+		logger.FromContext(ctx).Warn("giving up on batch: context canceled", slog.String("sink", rs.sink.Name()), slog.Any("error", err))
+		return
+	}
+
+	rs.metrics.batchesSent.Add(1)
+	rs.metrics.bytesSent.Add(estimateBytes(batch.logs))
+
+	if r.offsetStore != nil && batch.walOffsetOk {
+		if err := r.offsetStore.Commit(rs.sink.Name(), batch.walOffset); err != nil {
+			logger.FromContext(ctx).Warn("error committing wal offset", slog.String("sink", rs.sink.Name()), slog.Any("error", err))
+		}
+	}
+}
+
+func (r *Registry) reportStatus(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, rs := range r.sinks {
+				logger.Stdout.Info("sink status",
+					slog.String("sink", rs.sink.Name()),
+					slog.Int64("batches_sent", rs.metrics.batchesSent.Load()),
+					slog.Int64("bytes_sent", rs.metrics.bytesSent.Load()),
+					slog.Int64("errors", rs.metrics.errors.Load()),
+					slog.Int64("retries", rs.metrics.retries.Load()),
+					slog.Int64("dropped", rs.metrics.dropped.Load()),
+					slog.Int("queue_depth", len(rs.queue)),
+				)
+			}
+		}
+	}
+}
+
+func estimateBytes(batch []railway.EnvironmentLog) int64 {
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return 0
+	}
+
+	return int64(len(b))
+}
@@ -0,0 +1,193 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ferretcode/locomotive/railway"
+	"github.com/ferretcode/locomotive/wal"
+)
+
+// fakeSink is a minimal Sink used to exercise Registry without a real
+// network destination. Send fails until it's been called failUntil times.
+type fakeSink struct {
+	name      string
+	filter    SinkFilter
+	failUntil int
+
+	calls    int
+	received [][]railway.EnvironmentLog
+}
+
+func (s *fakeSink) Name() string       { return s.name }
+func (s *fakeSink) Filter() SinkFilter { return s.filter }
+
+func (s *fakeSink) Send(ctx context.Context, logs []railway.EnvironmentLog) error {
+	s.calls++
+
+	if s.calls <= s.failUntil {
+		return errors.New("temporary failure")
+	}
+
+	s.received = append(s.received, logs)
+
+	return nil
+}
+
+func TestDispatchFansOutPerSinkFilters(t *testing.T) {
+	errSink := &fakeSink{name: "errors-only", filter: SinkFilter{Levels: []string{"error"}}}
+	allSink := &fakeSink{name: "all-levels"}
+
+	r := NewRegistry()
+	r.Register(errSink)
+	r.Register(allSink)
+	r.Start(context.Background(), time.Hour)
+
+	logs := []railway.EnvironmentLog{
+		{Severity: "info", Message: "starting up"},
+		{Severity: "error", Message: "connection refused"},
+	}
+
+	r.Dispatch(context.Background(), logs, 0, false)
+
+	waitFor(t, func() bool { return len(errSink.received) > 0 })
+
+	if len(errSink.received[0]) != 1 || errSink.received[0][0].Severity != "error" {
+		t.Fatalf("expected the level-filtered sink to receive only the error log, got %v", errSink.received)
+	}
+
+	waitFor(t, func() bool { return len(allSink.received) > 0 })
+
+	if len(allSink.received[0]) != 2 {
+		t.Fatalf("expected the unfiltered sink to receive both logs, got %v", allSink.received)
+	}
+}
+
+func TestSendCommitsWalOffsetOnSuccess(t *testing.T) {
+	sink := &fakeSink{name: "otlp"}
+
+	r := NewRegistry()
+	r.Register(sink)
+
+	store, err := wal.NewOffsetStore(t.TempDir() + "/offsets.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.UseWAL(store)
+
+	r.send(context.Background(), r.sinks[0], sinkBatch{
+		ctx:         context.Background(),
+		logs:        []railway.EnvironmentLog{{Severity: "info", Message: "hi"}},
+		walOffset:   42,
+		walOffsetOk: true,
+	})
+
+	offset, ok := store.Get("otlp")
+	if !ok || offset != 42 {
+		t.Fatalf("expected the offset store to record offset 42 for sink %q, got (%d, %v)", "otlp", offset, ok)
+	}
+}
+
+func TestSendSkipsWalCommitOnFailure(t *testing.T) {
+	sink := &fakeSink{name: "otlp", failUntil: 100}
+
+	r := NewRegistry()
+	r.Register(sink)
+
+	store, err := wal.NewOffsetStore(t.TempDir() + "/offsets.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.UseWAL(store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	r.send(ctx, r.sinks[0], sinkBatch{
+		ctx:         context.Background(),
+		logs:        []railway.EnvironmentLog{{Severity: "info", Message: "hi"}},
+		walOffset:   42,
+		walOffsetOk: true,
+	})
+
+	if _, ok := store.Get("otlp"); ok {
+		t.Error("expected a failed send to not commit a wal offset")
+	}
+}
+
+// blockingSink's Send blocks until unblock is closed, then always succeeds.
+// It's used to hold a batch in send's retry loop indefinitely, the way a
+// sink that's down would, so a test can observe whether a later batch is
+// allowed to commit an offset past it.
+type blockingSink struct {
+	name    string
+	unblock chan struct{}
+}
+
+func (s *blockingSink) Name() string       { return s.name }
+func (s *blockingSink) Filter() SinkFilter { return SinkFilter{} }
+
+func (s *blockingSink) Send(ctx context.Context, logs []railway.EnvironmentLog) error {
+	select {
+	case <-s.unblock:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TestWorkerNeverCommitsPastAStillUnackedBatch guards the offset store's
+// core guarantee: a sink's acked offset is a single scalar, so it's only
+// safe to advance it in WAL order. A later batch's successful Commit must
+// never race ahead of an earlier batch that's still stuck retrying, or a
+// restart would replay from the wrong place and skip the stuck entry for
+// good.
+func TestWorkerNeverCommitsPastAStillUnackedBatch(t *testing.T) {
+	unblock := make(chan struct{})
+	sink := &blockingSink{name: "otlp", unblock: unblock}
+
+	r := NewRegistry()
+	r.Register(sink)
+
+	store, err := wal.NewOffsetStore(t.TempDir() + "/offsets.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.UseWAL(store)
+	r.Start(context.Background(), time.Hour)
+
+	r.Dispatch(context.Background(), []railway.EnvironmentLog{{Severity: "info", Message: "first"}}, 1, true)
+	r.Dispatch(context.Background(), []railway.EnvironmentLog{{Severity: "info", Message: "second"}}, 2, true)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if _, ok := store.Get("otlp"); ok {
+		t.Fatal("expected no offset to be committed while the first batch is still unacked")
+	}
+
+	close(unblock)
+
+	waitFor(t, func() bool {
+		offset, ok := store.Get("otlp")
+		return ok && offset == 2
+	})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
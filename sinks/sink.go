@@ -0,0 +1,32 @@
+// Package sinks formalizes the log forwarders (Discord, Slack, Loki, webhook,
+// OTLP, ...) behind a single Sink interface and fans batches out to all of
+// them concurrently via a Registry.
+package sinks
+
+import (
+	"context"
+
+	"github.com/ferretcode/locomotive/filter"
+	"github.com/ferretcode/locomotive/railway"
+)
+
+// SinkFilter holds the per-sink level and content filters that Dispatch
+// applies before a batch reaches a Sink.
+type SinkFilter struct {
+	Levels  []string
+	Content filter.FilterProgram
+}
+
+// Sink is a destination logs can be forwarded to.
+type Sink interface {
+	// Name identifies the sink in logs and metrics.
+	Name() string
+
+	// Filter returns the level/content filter to apply before Send is called.
+	Filter() SinkFilter
+
+	// Send delivers a batch of logs. A returned error is retried by the
+	// Registry with backoff; Send should be safe to call more than once with
+	// the same batch.
+	Send(ctx context.Context, logs []railway.EnvironmentLog) error
+}
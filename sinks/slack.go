@@ -0,0 +1,111 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ferretcode/locomotive/config"
+	"github.com/ferretcode/locomotive/railway"
+)
+
+// SlackSink forwards batches of logs to a Slack incoming webhook, one
+// message per batch.
+type SlackSink struct {
+	webhookUrl string
+	prettyJson bool
+	tags       []string
+	filter     SinkFilter
+
+	httpClient *http.Client
+}
+
+// NewSlackSink builds a SlackSink from cfg. It returns nil when
+// SLACK_WEBHOOK_URL isn't set, so callers can skip the sink entirely.
+func NewSlackSink(cfg *config.Config) *SlackSink {
+	if cfg.SlackWebhookUrl == "" {
+		return nil
+	}
+
+	return &SlackSink{
+		webhookUrl: cfg.SlackWebhookUrl,
+		prettyJson: cfg.SlackPrettyJson,
+		tags:       cfg.SlackTags,
+		filter: SinkFilter{
+			Levels:  cfg.LogsFilterSlack,
+			Content: cfg.LogsContentFilterProgramSlack,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *SlackSink) Name() string { return "slack" }
+
+func (s *SlackSink) Filter() SinkFilter { return s.filter }
+
+func (s *SlackSink) Send(ctx context.Context, logs []railway.EnvironmentLog) error {
+	text, err := formatSlackText(logs, s.prettyJson, s.tags)
+	if err != nil {
+		return fmt.Errorf("error formatting slack message: %w", err)
+	}
+
+	body, err := json.Marshal(&struct {
+		Text string `json:"text"`
+	}{Text: text})
+	if err != nil {
+		return fmt.Errorf("error marshalling slack payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building slack request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending slack request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// formatSlackText renders a batch as either one fenced JSON code block per
+// log (prettyJson) or a compact "LEVEL service: message" line per log,
+// prefixed with any configured tags so the right people get notified.
+func formatSlackText(logs []railway.EnvironmentLog, prettyJson bool, tags []string) (string, error) {
+	var builder strings.Builder
+
+	if len(tags) > 0 {
+		builder.WriteString(strings.Join(tags, " "))
+		builder.WriteString("\n")
+	}
+
+	for i := range logs {
+		if prettyJson {
+			data, err := json.MarshalIndent(logs[i], "", "  ")
+			if err != nil {
+				return "", err
+			}
+
+			builder.WriteString("```\n")
+			builder.Write(data)
+			builder.WriteString("\n```\n")
+		} else {
+			builder.WriteString(fmt.Sprintf("*%s* `%s`: %s\n", strings.ToUpper(logs[i].Severity), logs[i].Tags.ServiceName, logs[i].Message))
+		}
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,76 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ferretcode/locomotive/config"
+	"github.com/ferretcode/locomotive/railway"
+)
+
+// WebhookSink forwards batches of logs as a raw JSON array to a
+// generic HTTP endpoint.
+type WebhookSink struct {
+	endpoint string
+	headers  map[string]string
+	filter   SinkFilter
+
+	httpClient *http.Client
+}
+
+// NewWebhookSink builds a WebhookSink from cfg. It returns nil when
+// INGEST_URL isn't set, so callers can skip the sink entirely.
+func NewWebhookSink(cfg *config.Config) *WebhookSink {
+	if cfg.IngestUrl == "" {
+		return nil
+	}
+
+	return &WebhookSink{
+		endpoint: cfg.IngestUrl,
+		headers:  cfg.AdditionalHeaders,
+		filter: SinkFilter{
+			Levels:  cfg.LogsFilterWebhook,
+			Content: cfg.LogsContentFilterProgramWebhook,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Filter() SinkFilter { return s.filter }
+
+func (s *WebhookSink) Send(ctx context.Context, logs []railway.EnvironmentLog) error {
+	body, err := json.Marshal(logs)
+	if err != nil {
+		return fmt.Errorf("error marshalling webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status code %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package wal
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ferretcode/locomotive/logger"
+)
+
+// RunCompactor periodically trims WAL segments once every sink in sinkNames
+// has acked past them, and force-trims segments older than retention even if
+// a sink is stuck, so a dead sink can't grow the WAL forever. It returns once
+// ctx is canceled.
+func RunCompactor(ctx context.Context, writer *Writer, store *OffsetStore, sinkNames []string, retention time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if minAcked, ok := store.Min(sinkNames); ok {
+				if err := writer.Compact(minAcked); err != nil {
+					logger.Stdout.Warn("error compacting wal", slog.Any("error", err))
+				}
+			}
+
+			if retention <= 0 {
+				continue
+			}
+
+			dropped, err := writer.CompactOlderThan(time.Now().UTC().Add(-retention))
+			if err != nil {
+				logger.Stdout.Warn("error compacting wal by retention", slog.Any("error", err))
+				continue
+			}
+
+			if dropped {
+				logger.Stdout.Warn("wal retention reached before every sink acked, dropping stale segments", slog.Duration("retention", retention))
+			}
+		}
+	}
+}
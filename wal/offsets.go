@@ -0,0 +1,106 @@
+package wal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// OffsetStore persists, per sink, the offset each sink has durably
+// acknowledged so it can resume after a restart and so the Compactor knows
+// what's safe to trim.
+type OffsetStore struct {
+	path string
+
+	mu      sync.Mutex
+	offsets map[string]uint64
+}
+
+// NewOffsetStore loads (or creates) the offset file at path.
+func NewOffsetStore(path string) (*OffsetStore, error) {
+	s := &OffsetStore{path: path, offsets: make(map[string]uint64)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading wal offset store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.offsets); err != nil {
+		return nil, fmt.Errorf("error parsing wal offset store: %w", err)
+	}
+
+	return s, nil
+}
+
+// Get returns the last offset acked by sink, or (0, false) if it has never acked.
+func (s *OffsetStore) Get(sink string) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offset, ok := s.offsets[sink]
+
+	return offset, ok
+}
+
+// Commit records that sink has durably processed everything up to and
+// including offset, persisting the update to disk.
+func (s *OffsetStore) Commit(sink string, offset uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.offsets[sink]; ok && offset <= existing {
+		return nil
+	}
+
+	s.offsets[sink] = offset
+
+	return s.saveLocked()
+}
+
+// Min returns the lowest acked offset across the given sink names. ok is
+// false if any named sink hasn't acked yet, since it isn't safe to trim past
+// a sink the store knows nothing about.
+func (s *OffsetStore) Min(sinks []string) (offset uint64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := true
+
+	for _, sink := range sinks {
+		o, known := s.offsets[sink]
+		if !known {
+			return 0, false
+		}
+
+		if first || o < offset {
+			offset = o
+			first = false
+		}
+	}
+
+	return offset, !first
+}
+
+func (s *OffsetStore) saveLocked() error {
+	data, err := json.Marshal(s.offsets)
+	if err != nil {
+		return fmt.Errorf("error marshalling wal offset store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing wal offset store: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("error saving wal offset store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,461 @@
+// Package wal provides an on-disk write-ahead log that buffers accepted logs
+// so a crash or an extended sink outage doesn't lose anything that hasn't
+// been delivered yet. Every accepted log is appended with a monotonic offset
+// before it reaches any sink; an OffsetStore records how far each sink has
+// acked, and a Compactor trims segments once every known sink has acked past
+// them (see RunCompactor).
+package wal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	Offset uint64          `json:"offset"`
+	Time   time.Time       `json:"time"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type segment struct {
+	start uint64
+	end   uint64
+	path  string
+}
+
+// Writer appends entries to a rotating set of JSON-lines segment files under
+// a directory. Segment file names are the zero-padded offset of their first
+// entry, which keeps them in replay order under a plain lexicographic sort.
+type Writer struct {
+	dir      string
+	maxBytes int64
+
+	mu                sync.Mutex
+	segments          []segment
+	current           *os.File
+	currentPath       string
+	currentStart      uint64
+	currentEnd        uint64
+	currentHasEntries bool
+	currentLen        int64
+	nextOffset        uint64
+}
+
+// NewWriter opens (or creates) a WAL under dir, resuming the offset counter
+// from whatever was already written there.
+func NewWriter(dir string, maxBytes int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating wal directory: %w", err)
+	}
+
+	w := &Writer{dir: dir, maxBytes: maxBytes}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing wal segments: %w", err)
+	}
+
+	sort.Strings(files)
+
+	currentPath := segmentPath(dir, 0)
+
+	for i, path := range files {
+		start, perr := parseSegmentStart(path)
+		if perr != nil {
+			continue
+		}
+
+		if i == len(files)-1 {
+			currentPath = path
+			w.currentStart = start
+			continue
+		}
+
+		last, found, rerr := scanLast(path)
+		if rerr != nil {
+			return nil, fmt.Errorf("error reading wal segment %s: %w", path, rerr)
+		}
+
+		if found {
+			w.segments = append(w.segments, segment{start: start, end: last.Offset, path: path})
+		}
+	}
+
+	last, found, err := scanLast(currentPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading wal segment %s: %w", currentPath, err)
+	}
+
+	if found {
+		w.currentEnd = last.Offset
+		w.currentHasEntries = true
+		w.nextOffset = last.Offset + 1
+	} else {
+		w.nextOffset = w.currentStart
+	}
+
+	f, err := os.OpenFile(currentPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening wal segment: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error stat-ing wal segment: %w", err)
+	}
+
+	w.current = f
+	w.currentPath = currentPath
+	w.currentLen = info.Size()
+
+	return w, nil
+}
+
+// Append durably records data (typically a marshalled EnvironmentLog) and
+// returns the monotonic offset it was assigned.
+func (w *Writer) Append(data []byte) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	offset := w.nextOffset
+
+	line, err := json.Marshal(entry{Offset: offset, Time: time.Now().UTC(), Data: json.RawMessage(data)})
+	if err != nil {
+		return 0, fmt.Errorf("error marshalling wal entry: %w", err)
+	}
+
+	line = append(line, '\n')
+
+	if _, err := w.current.Write(line); err != nil {
+		return 0, fmt.Errorf("error appending to wal: %w", err)
+	}
+
+	w.currentLen += int64(len(line))
+	w.currentEnd = offset
+	w.currentHasEntries = true
+	w.nextOffset++
+
+	if w.currentLen >= w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return offset, err
+		}
+	}
+
+	return offset, nil
+}
+
+func (w *Writer) rotate() error {
+	if w.currentHasEntries {
+		w.segments = append(w.segments, segment{start: w.currentStart, end: w.currentEnd, path: w.currentPath})
+	}
+
+	if err := w.current.Close(); err != nil {
+		return fmt.Errorf("error closing wal segment: %w", err)
+	}
+
+	w.currentStart = w.nextOffset
+	w.currentPath = segmentPath(w.dir, w.currentStart)
+	w.currentHasEntries = false
+	w.currentLen = 0
+
+	f, err := os.OpenFile(w.currentPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error creating wal segment: %w", err)
+	}
+
+	w.current = f
+
+	return nil
+}
+
+// OldestTimestamp returns the timestamp of the oldest entry still retained in
+// the WAL. It's used to derive a resubscribe BeforeDate that covers
+// everything not yet acked by every sink.
+func (w *Writer) OldestTimestamp() (time.Time, bool) {
+	w.mu.Lock()
+	path := w.currentPath
+	if len(w.segments) > 0 {
+		path = w.segments[0].path
+	}
+	w.mu.Unlock()
+
+	first, found, err := scanFirst(path)
+	if err != nil || !found {
+		return time.Time{}, false
+	}
+
+	return first.Time, true
+}
+
+// Replay calls fn with every retained entry whose offset is greater than
+// from, in offset order. It's used by a sink to catch up after a restart.
+func (w *Writer) Replay(from uint64, fn func(offset uint64, data []byte) error) error {
+	w.mu.Lock()
+	paths := make([]string, 0, len(w.segments)+1)
+	for _, s := range w.segments {
+		paths = append(paths, s.path)
+	}
+	paths = append(paths, w.currentPath)
+	w.mu.Unlock()
+
+	for _, path := range paths {
+		if err := replaySegment(path, from, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseReplayFrom resolves a REPLAY_FROM value into the offset Replay should
+// start after: either a raw decimal WAL offset, or an RFC3339 timestamp
+// resolved against the WAL's own entries via offsetBefore.
+func (w *Writer) ParseReplayFrom(raw string) (uint64, error) {
+	if offset, err := strconv.ParseUint(raw, 10, 64); err == nil {
+		return offset, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return 0, fmt.Errorf("REPLAY_FROM must be a WAL offset or an RFC3339 timestamp: %w", err)
+	}
+
+	offset, _, err := w.offsetBefore(t)
+	if err != nil {
+		return 0, err
+	}
+
+	return offset, nil
+}
+
+// offsetBefore returns the offset of the newest retained entry older than t,
+// or ok=false if every retained entry is at or after t (in which case 0 is
+// the correct "replay everything" starting offset anyway).
+func (w *Writer) offsetBefore(t time.Time) (offset uint64, ok bool, err error) {
+	w.mu.Lock()
+	paths := make([]string, 0, len(w.segments)+1)
+	for _, s := range w.segments {
+		paths = append(paths, s.path)
+	}
+	paths = append(paths, w.currentPath)
+	w.mu.Unlock()
+
+	for _, path := range paths {
+		f, ferr := os.Open(path)
+		if errors.Is(ferr, os.ErrNotExist) {
+			continue
+		}
+		if ferr != nil {
+			return 0, false, ferr
+		}
+
+		stop := false
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			var e entry
+			if jerr := json.Unmarshal(scanner.Bytes(), &e); jerr != nil {
+				continue
+			}
+
+			if e.Time.Before(t) {
+				offset = e.Offset
+				ok = true
+				continue
+			}
+
+			stop = true
+			break
+		}
+
+		serr := scanner.Err()
+		f.Close()
+
+		if serr != nil {
+			return 0, false, serr
+		}
+
+		if stop {
+			return offset, ok, nil
+		}
+	}
+
+	return offset, ok, nil
+}
+
+// Compact removes closed segments whose entries are all <= minAcked, i.e.
+// every sink the caller tracks has acked past them.
+func (w *Writer) Compact(minAcked uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.segments[:0]
+
+	for _, s := range w.segments {
+		if s.end <= minAcked {
+			if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("error removing wal segment %s: %w", s.path, err)
+			}
+			continue
+		}
+
+		kept = append(kept, s)
+	}
+
+	w.segments = kept
+
+	return nil
+}
+
+// CompactOlderThan force-removes closed segments whose newest entry predates
+// cutoff, even if a sink hasn't acked past them. It's the WAL_RETENTION
+// safety net that keeps a permanently stuck sink from growing the WAL
+// forever; it reports whether anything un-acked was dropped so the caller
+// can log accordingly.
+func (w *Writer) CompactOlderThan(cutoff time.Time) (droppedUnacked bool, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.segments[:0]
+
+	for _, s := range w.segments {
+		last, found, rerr := scanLast(s.path)
+		if rerr != nil {
+			return droppedUnacked, fmt.Errorf("error reading wal segment %s: %w", s.path, rerr)
+		}
+
+		if found && last.Time.Before(cutoff) {
+			if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return droppedUnacked, fmt.Errorf("error removing wal segment %s: %w", s.path, err)
+			}
+
+			droppedUnacked = true
+
+			continue
+		}
+
+		kept = append(kept, s)
+	}
+
+	w.segments = kept
+
+	return droppedUnacked, nil
+}
+
+// Close closes the active segment file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current.Close()
+}
+
+func segmentPath(dir string, start uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d.jsonl", start))
+}
+
+func parseSegmentStart(path string) (uint64, error) {
+	name := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	return strconv.ParseUint(name, 10, 64)
+}
+
+func scanFirst(path string) (entry, bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entry{}, false, nil
+	}
+	if err != nil {
+		return entry{}, false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		return e, true, nil
+	}
+
+	return entry{}, false, scanner.Err()
+}
+
+func scanLast(path string) (entry, bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entry{}, false, nil
+	}
+	if err != nil {
+		return entry{}, false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var last entry
+	found := false
+
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		last = e
+		found = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return entry{}, false, err
+	}
+
+	return last, found, nil
+}
+
+func replaySegment(path string, from uint64, fn func(offset uint64, data []byte) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening wal segment %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		if e.Offset <= from {
+			continue
+		}
+
+		if err := fn(e.Offset, e.Data); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
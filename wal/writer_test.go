@@ -0,0 +1,149 @@
+package wal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendAndReplay(t *testing.T) {
+	w, err := NewWriter(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	offsets := make([]uint64, 0, 3)
+
+	for _, msg := range []string{"one", "two", "three"} {
+		offset, err := w.Append([]byte(`"` + msg + `"`))
+		if err != nil {
+			t.Fatalf("unexpected error appending %q: %v", msg, err)
+		}
+
+		offsets = append(offsets, offset)
+	}
+
+	if offsets[0] != 0 || offsets[1] != 1 || offsets[2] != 2 {
+		t.Fatalf("expected monotonically increasing offsets starting at 0, got %v", offsets)
+	}
+
+	var replayed []string
+
+	if err := w.Replay(0, func(offset uint64, data []byte) error {
+		replayed = append(replayed, string(data))
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error replaying: %v", err)
+	}
+
+	if len(replayed) != 2 || replayed[0] != `"two"` || replayed[1] != `"three"` {
+		t.Fatalf("expected Replay(0, ...) to skip offset 0 and return [two three], got %v", replayed)
+	}
+}
+
+func TestRotateSplitsSegments(t *testing.T) {
+	w, err := NewWriter(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := w.Append([]byte(`"entry"`)); err != nil {
+			t.Fatalf("unexpected error appending: %v", err)
+		}
+	}
+
+	if len(w.segments) == 0 {
+		t.Error("expected a tiny WAL_MAX_BYTES to have rotated at least one segment")
+	}
+
+	var replayed int
+
+	if err := w.Replay(0, func(offset uint64, data []byte) error {
+		replayed++
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error replaying across segments: %v", err)
+	}
+
+	if replayed != 4 {
+		t.Errorf("expected Replay to see all 4 entries after offset 0 across rotated segments, got %d", replayed)
+	}
+}
+
+func TestCompactRemovesFullyAckedSegments(t *testing.T) {
+	w, err := NewWriter(t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := w.Append([]byte(`"entry"`)); err != nil {
+			t.Fatalf("unexpected error appending: %v", err)
+		}
+	}
+
+	segmentsBefore := len(w.segments)
+	if segmentsBefore == 0 {
+		t.Fatal("expected rotation to have produced at least one closed segment")
+	}
+
+	if err := w.Compact(3); err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+
+	if len(w.segments) != 0 {
+		t.Errorf("expected Compact(3) to remove every segment whose entries are all acked, got %d segments remaining", len(w.segments))
+	}
+}
+
+func TestParseReplayFromOffset(t *testing.T) {
+	w, err := NewWriter(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	offset, err := w.ParseReplayFrom("7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if offset != 7 {
+		t.Errorf("expected a raw decimal REPLAY_FROM to parse to the same offset, got %d", offset)
+	}
+}
+
+func TestParseReplayFromTimestamp(t *testing.T) {
+	w, err := NewWriter(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Append([]byte(`"first"`)); err != nil {
+		t.Fatalf("unexpected error appending: %v", err)
+	}
+
+	cutoff := time.Now().UTC().Add(time.Second)
+
+	offset, err := w.ParseReplayFrom(cutoff.Format(time.RFC3339Nano))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var replayed []string
+
+	if err := w.Replay(offset, func(offset uint64, data []byte) error {
+		replayed = append(replayed, string(data))
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error replaying: %v", err)
+	}
+
+	if len(replayed) != 0 {
+		t.Errorf("expected replay after a cutoff past every entry to return nothing, got %v", replayed)
+	}
+}